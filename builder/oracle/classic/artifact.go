@@ -0,0 +1,50 @@
+package classic
+
+import "fmt"
+
+// Artifact is a machine image that was built by the Oracle Classic builder.
+type Artifact struct {
+	ImageListVersion int
+	MachineImageName string
+	MachineImageFile string
+
+	// ObjectStorageURLs holds the URL(s) of the image tarball after it has
+	// been uploaded to OCI Object Storage, if object storage export was
+	// configured. It is empty otherwise.
+	ObjectStorageURLs []string
+}
+
+// BuilderId implements packer.Artifact.
+func (a *Artifact) BuilderId() string {
+	return BuilderId
+}
+
+// Files implements packer.Artifact. The machine image lives in the Oracle
+// Classic compute image list, not on local disk, so there are no files to
+// report.
+func (a *Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	return fmt.Sprintf("%s:%d", a.MachineImageName, a.ImageListVersion)
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("Oracle Classic image: %s (image list version %d)", a.MachineImageName, a.ImageListVersion)
+}
+
+func (a *Artifact) State(name string) interface{} {
+	switch name {
+	case "ObjectStorageURLs":
+		return a.ObjectStorageURLs
+	}
+	return nil
+}
+
+// Destroy removes the machine image associated with this artifact. Oracle
+// Classic does not currently support deleting machine images through this
+// builder, so Destroy is a no-op.
+func (a *Artifact) Destroy() error {
+	return nil
+}
@@ -3,7 +3,9 @@ package classic
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"sync"
 
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/go-oracle-terraform/compute"
@@ -21,7 +23,9 @@ const BuilderId = "packer.oracle.classic"
 // Builder is a builder implementation that creates Oracle OCI custom images.
 type Builder struct {
 	config *Config
-	runner multistep.Runner
+
+	runnersLock sync.Mutex
+	runners     []multistep.Runner
 }
 
 func (b *Builder) Prepare(rawConfig ...interface{}) ([]string, error) {
@@ -41,58 +45,185 @@ func (b *Builder) Prepare(rawConfig ...interface{}) ([]string, error) {
 	return nil, nil
 }
 
+// imageMatrixEntry is one combination of shape, source image list entry and
+// region to build an image for.
+type imageMatrixEntry struct {
+	Index                int
+	Shape                string
+	SourceImageListEntry int
+	Region               string
+	// RegionMatrixed is true when Region came from Config.Regions (the
+	// plural matrix field) rather than from falling back to the singular
+	// Config.Region. Only RegionMatrixed entries require a matching
+	// Config.RegionAPIEndpoints entry; a template that sets nothing but
+	// the singular region field keeps targeting Config.APIEndpoint, since
+	// it never opted into the matrix feature.
+	RegionMatrixed bool
+}
+
+// matrix expands Config.Shapes, Config.SourceImageListEntries and
+// Config.Regions into the cartesian product of combinations to build. Any
+// matrix field left empty falls back to its single-valued counterpart, so a
+// config with none of the matrix fields set still produces exactly one
+// entry, matching the builder's original single-image behavior.
+func (b *Builder) matrix() []imageMatrixEntry {
+	shapes := b.config.Shapes
+	if len(shapes) == 0 {
+		shapes = []string{b.config.Shape}
+	}
+	entries := b.config.SourceImageListEntries
+	if len(entries) == 0 {
+		entries = []int{b.config.SourceImageListEntry}
+	}
+	regions := b.config.Regions
+	regionMatrixed := len(regions) > 0
+	if !regionMatrixed {
+		regions = []string{b.config.Region}
+	}
+
+	var matrix []imageMatrixEntry
+	for _, shape := range shapes {
+		for _, entry := range entries {
+			for _, region := range regions {
+				matrix = append(matrix, imageMatrixEntry{
+					Index:                len(matrix),
+					Shape:                shape,
+					SourceImageListEntry: entry,
+					Region:               region,
+					RegionMatrixed:       regionMatrixed,
+				})
+			}
+		}
+	}
+	return matrix
+}
+
+// resolveAPIEndpoint returns the API endpoint to build entry against: its
+// region-specific endpoint from Config.RegionAPIEndpoints when entry came
+// from the Regions matrix field, or Config.APIEndpoint otherwise.
+func resolveAPIEndpoint(config *Config, entry imageMatrixEntry) (*url.URL, error) {
+	if !entry.RegionMatrixed || entry.Region == "" {
+		return config.apiEndpointURL, nil
+	}
+	u, ok := config.regionAPIEndpointURLs[entry.Region]
+	if !ok {
+		return nil, fmt.Errorf("no api endpoint configured for region %q", entry.Region)
+	}
+	return u, nil
+}
+
+// Run builds one image per entry in the matrix, concurrently, and
+// aggregates the results into a single packer.Artifact.
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	matrix := b.matrix()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		errs      *packer.MultiError
+		artifacts []*Artifact
+	)
+
+	for _, entry := range matrix {
+		wg.Add(1)
+		go func(entry imageMatrixEntry) {
+			defer wg.Done()
+
+			artifact, err := b.buildOne(ui, hook, entry)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = packer.MultiErrorAppend(errs, fmt.Errorf("%s (shape %s, image list entry %d): %s", entry.Region, entry.Shape, entry.SourceImageListEntry, err))
+				return
+			}
+			if artifact != nil {
+				artifacts = append(artifacts, artifact)
+			}
+		}(entry)
+	}
+	wg.Wait()
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, errs
+	}
+	if len(artifacts) == 0 {
+		return nil, nil
+	}
+	if len(artifacts) == 1 {
+		return artifacts[0], nil
+	}
+	return &MultiArtifact{Artifacts: artifacts}, nil
+}
+
+// buildOne runs the full step chain for a single matrix entry and returns
+// the resulting artifact. Every entry gets its own state bag, key pair and
+// IP reservation so that concurrent builds don't trample each other.
+func (b *Builder) buildOne(ui packer.Ui, hook packer.Hook, entry imageMatrixEntry) (*Artifact, error) {
+	config := *b.config
+	config.Shape = entry.Shape
+	config.SourceImageListEntry = entry.SourceImageListEntry
+
+	apiEndpointURL, err := resolveAPIEndpoint(&config, entry)
+	if err != nil {
+		return nil, err
+	}
+
 	loggingEnabled := os.Getenv("PACKER_OCI_CLASSIC_LOGGING") != ""
 	httpClient := cleanhttp.DefaultClient()
-	config := &opc.Config{
-		Username:       opc.String(b.config.Username),
-		Password:       opc.String(b.config.Password),
-		IdentityDomain: opc.String(b.config.IdentityDomain),
-		APIEndpoint:    b.config.apiEndpointURL,
+	opcConfig := &opc.Config{
+		Username:       opc.String(config.Username),
+		Password:       opc.String(config.Password),
+		IdentityDomain: opc.String(config.IdentityDomain),
+		APIEndpoint:    apiEndpointURL,
 		LogLevel:       opc.LogDebug,
 		Logger:         &Logger{loggingEnabled},
 		// Logger: # Leave blank to use the default logger, or provide your own
 		HTTPClient: httpClient,
 	}
 	// Create the Compute Client
-	client, err := compute.NewComputeClient(config)
+	client, err := compute.NewComputeClient(opcConfig)
 	if err != nil {
 		return nil, fmt.Errorf("Error creating OPC Compute Client: %s", err)
 	}
 
-	runID := os.Getenv("PACKER_RUN_UUID")
+	runID := fmt.Sprintf("%s-%d", os.Getenv("PACKER_RUN_UUID"), entry.Index)
 	// Populate the state bag
 	state := new(multistep.BasicStateBag)
-	state.Put("config", b.config)
+	state.Put("config", &config)
 	state.Put("hook", hook)
 	state.Put("ui", ui)
 	state.Put("client", client)
 	state.Put("run_id", runID)
 
 	var steps []multistep.Step
-	if b.config.IsPV() {
-		builderCommConfig := b.config.Comm
+	if config.IsPV() {
+		builderCommConfig := config.Comm
 		builderCommConfig.SSHPty = true
 
 		steps = []multistep.Step{
 			&stepCreatePersistentVolume{
-				VolumeSize:     fmt.Sprintf("%d", b.config.PersistentVolumeSize),
+				VolumeSize:     fmt.Sprintf("%d", config.PersistentVolumeSize),
 				VolumeName:     fmt.Sprintf("master-storage_%s", runID),
-				ImageList:      b.config.SourceImageList,
-				ImageListEntry: b.config.SourceImageListEntry,
+				ImageList:      config.SourceImageList,
+				ImageListEntry: config.SourceImageListEntry,
 				Bootable:       true,
 			},
 			&stepCreatePersistentVolume{
 				// We double the master volume size because we need room to
 				// tarball the disk image. We also need to chunk the tar ball,
-				// but we can remove the original disk image first.
-				VolumeSize: fmt.Sprintf("%d", b.config.PersistentVolumeSize*2),
+				// but we can remove the original disk image first. Picking
+				// export_format = "zstd" compresses the tarball faster than
+				// the default gzip, but doesn't change how much
+				// scratch room the uncompressed tar and source volume need,
+				// so the volume is still sized for the worst case here.
+				VolumeSize: fmt.Sprintf("%d", config.PersistentVolumeSize*2),
 				VolumeName: fmt.Sprintf("builder-storage_%s", runID),
 			},
 			&ocommon.StepKeyPair{
-				Debug:        b.config.PackerDebug,
-				Comm:         &b.config.Comm,
-				DebugKeyPath: fmt.Sprintf("oci_classic_%s.pem", b.config.PackerBuildName),
+				Debug:        config.PackerDebug,
+				Comm:         &config.Comm,
+				DebugKeyPath: fmt.Sprintf("oci_classic_%s_%d.pem", config.PackerBuildName, entry.Index),
 			},
 			&stepCreateIPReservation{},
 			&stepAddKeysToAPI{},
@@ -101,10 +232,14 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 				Name:       fmt.Sprintf("master-instance_%s", runID),
 				VolumeName: fmt.Sprintf("master-storage_%s", runID),
 			},
+			&stepWaitForWindowsPassword{
+				Comm:            &config.Comm,
+				InstanceInfoKey: "master_instance_info",
+			},
 			&communicator.StepConnect{
-				Config:    &b.config.Comm,
+				Config:    &config.Comm,
 				Host:      ocommon.CommHost,
-				SSHConfig: b.config.Comm.SSHConfigFunc(),
+				SSHConfig: config.Comm.SSHConfigFunc(),
 			},
 			&common.StepProvision{},
 			&stepTerminatePVMaster{},
@@ -117,40 +252,51 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 				Index:           2,
 				InstanceInfoKey: "builder_instance_info",
 			},
+			&stepWaitForWindowsPassword{
+				Comm:            &builderCommConfig,
+				InstanceInfoKey: "builder_instance_info",
+			},
 			&communicator.StepConnect{
 				Config:    &builderCommConfig,
 				Host:      ocommon.CommHost,
-				SSHConfig: b.config.Comm.SSHConfigFunc(),
+				SSHConfig: config.Comm.SSHConfigFunc(),
 			},
-			&stepUploadImage{
-				UploadImageCommand: b.config.BuilderUploadImageCommand,
+			&stepExportImage{
+				UploadImageCommand: config.BuilderUploadImageCommand,
+				ExportFormat:       config.ExportFormat,
+				ExportChunkSize:    config.ExportChunkSize,
 			},
 			&stepCreateImage{},
+			&stepExportToObjectStorage{},
 			&stepListImages{},
 			&common.StepCleanupTempKeys{
-				Comm: &b.config.Comm,
+				Comm: &config.Comm,
 			},
 		}
 	} else {
 		// Build the steps
 		steps = []multistep.Step{
 			&ocommon.StepKeyPair{
-				Debug:        b.config.PackerDebug,
-				Comm:         &b.config.Comm,
-				DebugKeyPath: fmt.Sprintf("oci_classic_%s.pem", b.config.PackerBuildName),
+				Debug:        config.PackerDebug,
+				Comm:         &config.Comm,
+				DebugKeyPath: fmt.Sprintf("oci_classic_%s_%d.pem", config.PackerBuildName, entry.Index),
 			},
 			&stepCreateIPReservation{},
 			&stepAddKeysToAPI{},
 			&stepSecurity{},
 			&stepCreateInstance{},
+			&stepWaitForWindowsPassword{
+				Comm:            &config.Comm,
+				InstanceInfoKey: "instance_info",
+			},
 			&communicator.StepConnect{
-				Config:    &b.config.Comm,
+				Config:    &config.Comm,
 				Host:      ocommon.CommHost,
-				SSHConfig: b.config.Comm.SSHConfigFunc(),
+				SSHConfig: config.Comm.SSHConfigFunc(),
 			},
 			&common.StepProvision{},
 			&common.StepCleanupTempKeys{
-				Comm: &b.config.Comm,
+				Comm: &config.Comm,
 			},
 			&stepSnapshot{},
 			&stepListImages{},
@@ -158,8 +304,12 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	}
 
 	// Run the steps
-	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
-	b.runner.Run(state)
+	runner := common.NewRunner(steps, config.PackerConfig, ui)
+	b.runnersLock.Lock()
+	b.runners = append(b.runners, runner)
+	b.runnersLock.Unlock()
+
+	runner.Run(state)
 
 	// If there was an error, return that
 	if rawErr, ok := state.GetOk("error"); ok {
@@ -177,14 +327,20 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		MachineImageName: state.Get("machine_image_name").(string),
 		MachineImageFile: state.Get("machine_image_file").(string),
 	}
+	if urls, ok := state.GetOk("object_storage_urls"); ok {
+		artifact.ObjectStorageURLs = urls.([]string)
+	}
 
 	return artifact, nil
 }
 
-// Cancel terminates a running build.
+// Cancel terminates all running builds.
 func (b *Builder) Cancel() {
-	if b.runner != nil {
+	b.runnersLock.Lock()
+	defer b.runnersLock.Unlock()
+
+	for _, runner := range b.runners {
 		log.Println("Cancelling the step runner...")
-		b.runner.Cancel()
+		runner.Cancel()
 	}
 }
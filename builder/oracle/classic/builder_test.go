@@ -0,0 +1,104 @@
+package classic
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestBuilderMatrix_singleEntryWhenNoMatrixFieldsSet(t *testing.T) {
+	b := &Builder{config: &Config{
+		Shape:                "oc3",
+		SourceImageListEntry: 1,
+		Region:               "",
+	}}
+
+	matrix := b.matrix()
+	if len(matrix) != 1 {
+		t.Fatalf("expected exactly one entry, got %d: %+v", len(matrix), matrix)
+	}
+
+	got := matrix[0]
+	if got.Shape != "oc3" || got.SourceImageListEntry != 1 || got.Region != "" {
+		t.Fatalf("expected matrix to fall back to the singular fields, got %+v", got)
+	}
+}
+
+func TestBuilderMatrix_cartesianProduct(t *testing.T) {
+	b := &Builder{config: &Config{
+		Shapes:                 []string{"oc3", "oc4"},
+		SourceImageListEntries: []int{1, 2},
+		Regions:                []string{"us-ashburn-1", "us-phoenix-1"},
+	}}
+
+	matrix := b.matrix()
+	if len(matrix) != 8 {
+		t.Fatalf("expected 2x2x2=8 entries, got %d: %+v", len(matrix), matrix)
+	}
+
+	seen := make(map[string]bool)
+	for _, entry := range matrix {
+		key := entry.Shape + "|" + entry.Region
+		seen[key] = true
+	}
+	for _, shape := range []string{"oc3", "oc4"} {
+		for _, region := range []string{"us-ashburn-1", "us-phoenix-1"} {
+			if !seen[shape+"|"+region] {
+				t.Fatalf("expected matrix to include shape %q / region %q, got %+v", shape, region, matrix)
+			}
+		}
+	}
+}
+
+func TestBuilderMatrix_regionsFallsBackToSingularRegion(t *testing.T) {
+	b := &Builder{config: &Config{
+		Shape:                "oc3",
+		SourceImageListEntry: 1,
+		Region:               "us-ashburn-1",
+	}}
+
+	matrix := b.matrix()
+	if len(matrix) != 1 {
+		t.Fatalf("expected exactly one entry, got %d: %+v", len(matrix), matrix)
+	}
+	if matrix[0].Region != "us-ashburn-1" {
+		t.Fatalf("expected matrix to fall back to the singular Region field, got %q", matrix[0].Region)
+	}
+	if matrix[0].RegionMatrixed {
+		t.Fatal("expected RegionMatrixed to be false when Region came from the singular fallback")
+	}
+}
+
+func TestResolveAPIEndpoint_singularRegionIsDescriptiveOnly(t *testing.T) {
+	defaultEndpoint := &url.URL{Scheme: "https", Host: "api.example.com"}
+	config := &Config{apiEndpointURL: defaultEndpoint}
+
+	entry := imageMatrixEntry{Region: "us-ashburn-1", RegionMatrixed: false}
+
+	got, err := resolveAPIEndpoint(config, entry)
+	if err != nil {
+		t.Fatalf("expected no error for a non-matrixed region, got: %s", err)
+	}
+	if got != defaultEndpoint {
+		t.Fatalf("expected the singular region to keep targeting Config.APIEndpoint, got %v", got)
+	}
+}
+
+func TestResolveAPIEndpoint_matrixedRegionRequiresEndpoint(t *testing.T) {
+	config := &Config{apiEndpointURL: &url.URL{Scheme: "https", Host: "api.example.com"}}
+	entry := imageMatrixEntry{Region: "us-ashburn-1", RegionMatrixed: true}
+
+	if _, err := resolveAPIEndpoint(config, entry); err == nil {
+		t.Fatal("expected an error when a matrixed region has no region_api_endpoints entry")
+	}
+
+	regionEndpoint := &url.URL{Scheme: "https", Host: "ashburn.example.com"}
+	config.regionAPIEndpointURLs = map[string]*url.URL{"us-ashburn-1": regionEndpoint}
+
+	got, err := resolveAPIEndpoint(config, entry)
+	if err != nil {
+		t.Fatalf("expected no error once region_api_endpoints is set, got: %s", err)
+	}
+	if got != regionEndpoint {
+		t.Fatalf("expected the matrixed region's endpoint to be used, got %v", got)
+	}
+}
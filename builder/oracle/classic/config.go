@@ -0,0 +1,167 @@
+package classic
+
+import (
+	"fmt"
+	"net/url"
+
+	ocommon "github.com/hashicorp/packer/builder/oracle/common"
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// Config is the configuration structure for the Oracle Classic builder.
+// Builder.Prepare builds and validates it through NewConfig.
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+	Comm                communicator.Config `mapstructure:",squash"`
+	ctx                 interpolate.Context
+
+	Username       string `mapstructure:"username"`
+	Password       string `mapstructure:"password"`
+	IdentityDomain string `mapstructure:"identity_domain"`
+	APIEndpoint    string `mapstructure:"api_endpoint"`
+	apiEndpointURL *url.URL
+
+	AvailabilityDomain   string `mapstructure:"availability_domain"`
+	Shape                string `mapstructure:"shape"`
+	SourceImageList      string `mapstructure:"source_image_list"`
+	SourceImageListEntry int    `mapstructure:"source_image_list_entry"`
+	ImageName            string `mapstructure:"image_name"`
+	// Region is purely descriptive: it does not opt into per-region API
+	// endpoint targeting, and every build still goes to APIEndpoint. Set
+	// Regions (and RegionAPIEndpoints) instead to actually target multiple
+	// regions.
+	Region string `mapstructure:"region"`
+
+	// Shapes, SourceImageListEntries and Regions let a single build produce
+	// a matrix of images: one per combination of the three. Any of them
+	// left empty falls back to the corresponding singular field above, so
+	// templates that don't use the matrix keep building exactly one image.
+	//
+	// Every value in Regions other than "" must have a matching entry in
+	// RegionAPIEndpoints: that's the API endpoint buildOne targets for that
+	// region's combinations, instead of APIEndpoint. The singular Region
+	// field above is exempt from this requirement since it never opts into
+	// the matrix.
+	Shapes                 []string          `mapstructure:"shapes"`
+	SourceImageListEntries []int             `mapstructure:"source_image_list_entries"`
+	Regions                []string          `mapstructure:"regions"`
+	RegionAPIEndpoints     map[string]string `mapstructure:"region_api_endpoints"`
+	regionAPIEndpointURLs  map[string]*url.URL
+
+	PersistentVolumeSize int64 `mapstructure:"persistent_volume_size"`
+
+	// BuilderUploadImageCommand overrides the entire tar/compress/split/
+	// upload pipeline with a single, user-supplied shell command. When
+	// unset, the builder uses ExportFormat to drive the built-in
+	// ImageExporter pipeline instead.
+	BuilderUploadImageCommand string `mapstructure:"builder_upload_image_command"`
+
+	// ExportFormat selects the compressor used to shrink the machine image
+	// tarball before it is chunked and uploaded: "gzip" (the default),
+	// "zstd" or "xz". zstd trades a little CPU for 3-5x faster compression
+	// than gzip at a comparable ratio, which helps most on the large PV
+	// images this pipeline exists for.
+	ExportFormat string `mapstructure:"export_format"`
+	// ExportChunkSize is the size, in bytes, of each uploaded chunk. It
+	// defaults to exportDefaultChunkSize.
+	ExportChunkSize int64 `mapstructure:"export_chunk_size"`
+
+	// ObjectStorageNamespace, ObjectStorageBucket, ObjectStorageAccessURI and
+	// ObjectStorageObjectPrefix are optional. When ObjectStorageBucket is
+	// set, the finished machine image tarball is uploaded to OCI Object
+	// Storage after stepCreateImage finishes, in addition to being
+	// registered as a machine image.
+	ObjectStorageNamespace    string `mapstructure:"object_storage_namespace"`
+	ObjectStorageBucket       string `mapstructure:"object_storage_bucket"`
+	ObjectStorageAccessURI    string `mapstructure:"object_storage_access_uri"`
+	ObjectStorageObjectPrefix string `mapstructure:"object_storage_object_prefix"`
+
+	PVConfig ocommon.PVConfig `mapstructure:",squash"`
+}
+
+// NewConfig parses and validates the raw config into a Config.
+func NewConfig(raws ...interface{}) (*Config, error) {
+	c := new(Config)
+
+	err := config.Decode(c, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &c.ctx,
+	}, raws...)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs *packer.MultiError
+
+	if c.Username == "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("username must be specified"))
+	}
+	if c.Password == "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("password must be specified"))
+	}
+	if c.IdentityDomain == "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("identity_domain must be specified"))
+	}
+	if c.APIEndpoint != "" {
+		u, err := url.Parse(c.APIEndpoint)
+		if err != nil {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("could not parse api_endpoint: %s", err))
+		}
+		c.apiEndpointURL = u
+	}
+
+	if len(c.Regions) > 0 {
+		c.regionAPIEndpointURLs = make(map[string]*url.URL, len(c.Regions))
+		for _, region := range c.Regions {
+			if region == "" {
+				continue
+			}
+			endpoint, ok := c.RegionAPIEndpoints[region]
+			if !ok || endpoint == "" {
+				errs = packer.MultiErrorAppend(errs, fmt.Errorf("region %q is listed in regions but has no matching entry in region_api_endpoints", region))
+				continue
+			}
+			u, err := url.Parse(endpoint)
+			if err != nil {
+				errs = packer.MultiErrorAppend(errs, fmt.Errorf("could not parse region_api_endpoints[%q]: %s", region, err))
+				continue
+			}
+			c.regionAPIEndpointURLs[region] = u
+		}
+	}
+
+	if c.ObjectStorageBucket != "" {
+		if c.ObjectStorageNamespace == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("object_storage_namespace must be specified when object_storage_bucket is set"))
+		}
+		if c.ObjectStorageAccessURI == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("object_storage_access_uri must be specified when object_storage_bucket is set"))
+		}
+	}
+
+	if c.ExportFormat == "" {
+		c.ExportFormat = "gzip"
+	}
+	if _, ok := imageExporters[c.ExportFormat]; !ok {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("export_format must be one of gzip, zstd or xz, got %q", c.ExportFormat))
+	}
+	if c.ExportChunkSize == 0 {
+		c.ExportChunkSize = exportDefaultChunkSize
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, errs
+	}
+
+	return c, nil
+}
+
+// IsPV returns true if this config builds by snapshotting a persistent-volume
+// master instance rather than a running instance.
+func (c *Config) IsPV() bool {
+	return c.PersistentVolumeSize > 0
+}
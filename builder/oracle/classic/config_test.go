@@ -0,0 +1,68 @@
+package classic
+
+import "testing"
+
+func validRawConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"username":        "user",
+		"password":        "pass",
+		"identity_domain": "domain",
+	}
+}
+
+func TestNewConfig_exportFormatDefaultsToGzip(t *testing.T) {
+	c, err := NewConfig(validRawConfig())
+	if err != nil {
+		t.Fatalf("NewConfig returned error: %s", err)
+	}
+	if c.ExportFormat != "gzip" {
+		t.Fatalf("expected export_format to default to gzip, got %q", c.ExportFormat)
+	}
+	if c.ExportChunkSize != exportDefaultChunkSize {
+		t.Fatalf("expected export_chunk_size to default to %d, got %d", exportDefaultChunkSize, c.ExportChunkSize)
+	}
+}
+
+func TestNewConfig_exportFormatRejectsUnknownValue(t *testing.T) {
+	raw := validRawConfig()
+	raw["export_format"] = "bzip2"
+
+	if _, err := NewConfig(raw); err == nil {
+		t.Fatal("expected NewConfig to reject an unknown export_format, got nil error")
+	}
+}
+
+func TestNewConfig_objectStorageRequiresNamespaceAndAccessURI(t *testing.T) {
+	raw := validRawConfig()
+	raw["object_storage_bucket"] = "images"
+
+	if _, err := NewConfig(raw); err == nil {
+		t.Fatal("expected NewConfig to require object_storage_namespace and object_storage_access_uri, got nil error")
+	}
+
+	raw["object_storage_namespace"] = "ns"
+	raw["object_storage_access_uri"] = "https://objectstorage.example.com"
+
+	if _, err := NewConfig(raw); err != nil {
+		t.Fatalf("expected NewConfig to succeed once namespace and access uri are set, got: %s", err)
+	}
+}
+
+func TestNewConfig_regionsRequireMatchingAPIEndpoint(t *testing.T) {
+	raw := validRawConfig()
+	raw["regions"] = []string{"us-ashburn-1"}
+
+	if _, err := NewConfig(raw); err == nil {
+		t.Fatal("expected NewConfig to reject a region with no matching region_api_endpoints entry, got nil error")
+	}
+
+	raw["region_api_endpoints"] = map[string]string{"us-ashburn-1": "https://ashburn.example.com"}
+
+	c, err := NewConfig(raw)
+	if err != nil {
+		t.Fatalf("expected NewConfig to succeed once region_api_endpoints is set, got: %s", err)
+	}
+	if _, ok := c.regionAPIEndpointURLs["us-ashburn-1"]; !ok {
+		t.Fatal("expected regionAPIEndpointURLs to contain the configured region")
+	}
+}
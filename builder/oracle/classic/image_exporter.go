@@ -0,0 +1,74 @@
+package classic
+
+import "fmt"
+
+// exportDefaultChunkSize is the default size, in bytes, of each chunk the
+// machine image tarball is split into before upload (1 GiB).
+const exportDefaultChunkSize int64 = 1 << 30
+
+// ImageExporter builds the shell commands used to turn the builder
+// instance's attached master volume into a set of uploaded chunks ready for
+// stepCreateImage to register as a machine image. Each method returns a
+// command to be run remotely, via the instance's communicator, rather than
+// running it itself; implementations differ only in the compressor they
+// shell out to.
+type ImageExporter interface {
+	// Tar returns the command that archives srcDevice into destPath.
+	Tar(srcDevice, destPath string) string
+	// Compress returns the command that compresses path in place, and the
+	// path of the resulting compressed file, which callers must pass to
+	// Split instead of re-deriving it.
+	Compress(path string) (command, resultPath string)
+	// Split returns the command that breaks path into chunkSize-byte
+	// pieces named path.part00, path.part01, and so on, and the glob that
+	// matches every chunk it produces.
+	Split(path string, chunkSize int64) (command, chunkGlob string)
+	// Upload returns the command that uploads every chunk matching
+	// chunkGlob, in order.
+	Upload(chunkGlob string) string
+}
+
+// imageExporters maps Config.ExportFormat values to their ImageExporter
+// constructor.
+var imageExporters = map[string]func() ImageExporter{
+	"gzip": func() ImageExporter { return &shellImageExporter{compressCommand: "gzip", compressedExt: ".gz"} },
+	"zstd": func() ImageExporter {
+		return &shellImageExporter{compressCommand: "zstd -T0 --rm", compressedExt: ".zst"}
+	},
+	"xz": func() ImageExporter { return &shellImageExporter{compressCommand: "xz", compressedExt: ".xz"} },
+}
+
+// NewImageExporter returns the ImageExporter registered for format.
+func NewImageExporter(format string) (ImageExporter, error) {
+	newExporter, ok := imageExporters[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown export_format %q", format)
+	}
+	return newExporter(), nil
+}
+
+// shellImageExporter implements ImageExporter with tar, the configured
+// compressor, split, and curl, matching how the classic builder's original,
+// hardcoded upload pipeline worked.
+type shellImageExporter struct {
+	compressCommand string
+	compressedExt   string
+}
+
+func (e *shellImageExporter) Tar(srcDevice, destPath string) string {
+	return fmt.Sprintf("sudo tar -cf %s %s", destPath, srcDevice)
+}
+
+func (e *shellImageExporter) Compress(path string) (command, resultPath string) {
+	resultPath = path + e.compressedExt
+	return fmt.Sprintf("%s %s", e.compressCommand, path), resultPath
+}
+
+func (e *shellImageExporter) Split(path string, chunkSize int64) (command, chunkGlob string) {
+	chunkGlob = path + ".part*"
+	return fmt.Sprintf("split -d -b %d %s %s.part", chunkSize, path, path), chunkGlob
+}
+
+func (e *shellImageExporter) Upload(chunkGlob string) string {
+	return fmt.Sprintf("for f in %s; do curl -X PUT -T \"$f\" \"$UPLOAD_URL/$(basename $f)\"; done", chunkGlob)
+}
@@ -0,0 +1,52 @@
+package classic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewImageExporter_knownFormats(t *testing.T) {
+	for _, format := range []string{"gzip", "zstd", "xz"} {
+		if _, err := NewImageExporter(format); err != nil {
+			t.Errorf("expected %q to be a known export_format, got error: %s", format, err)
+		}
+	}
+}
+
+func TestNewImageExporter_unknownFormat(t *testing.T) {
+	if _, err := NewImageExporter("bzip2"); err == nil {
+		t.Fatal("expected an unknown export_format to return an error")
+	}
+}
+
+func TestShellImageExporter_zstdRemovesSourceTar(t *testing.T) {
+	exporter, err := NewImageExporter("zstd")
+	if err != nil {
+		t.Fatalf("NewImageExporter returned error: %s", err)
+	}
+
+	command, resultPath := exporter.Compress("/tmp/image.tar")
+	if resultPath != "/tmp/image.tar.zst" {
+		t.Fatalf("expected compressed path /tmp/image.tar.zst, got %q", resultPath)
+	}
+	if !strings.Contains(command, "--rm") {
+		t.Fatalf("expected zstd command to pass --rm so the source tar is freed, got %q", command)
+	}
+}
+
+func TestShellImageExporter_splitUsesCompressedPath(t *testing.T) {
+	exporter, err := NewImageExporter("gzip")
+	if err != nil {
+		t.Fatalf("NewImageExporter returned error: %s", err)
+	}
+
+	_, compressedPath := exporter.Compress("/tmp/image.tar")
+	command, chunkGlob := exporter.Split(compressedPath, 1024)
+
+	if !strings.Contains(command, compressedPath) {
+		t.Fatalf("expected split command to reference the concrete compressed path %q, got %q", compressedPath, command)
+	}
+	if chunkGlob != compressedPath+".part*" {
+		t.Fatalf("expected chunk glob to be derived from the compressed path, got %q", chunkGlob)
+	}
+}
@@ -0,0 +1,54 @@
+package classic
+
+import "strings"
+
+// MultiArtifact wraps the artifacts produced by a matrix build (see
+// Builder.matrix) into a single packer.Artifact, so that post-processors
+// downstream of a multi-image build see one artifact carrying every
+// ImageListVersion/MachineImageName tuple that was built.
+type MultiArtifact struct {
+	Artifacts []*Artifact
+}
+
+func (a *MultiArtifact) BuilderId() string {
+	return BuilderId
+}
+
+func (a *MultiArtifact) Files() []string {
+	return nil
+}
+
+func (a *MultiArtifact) Id() string {
+	ids := make([]string, len(a.Artifacts))
+	for i, artifact := range a.Artifacts {
+		ids[i] = artifact.Id()
+	}
+	return strings.Join(ids, ",")
+}
+
+func (a *MultiArtifact) String() string {
+	descriptions := make([]string, len(a.Artifacts))
+	for i, artifact := range a.Artifacts {
+		descriptions[i] = artifact.String()
+	}
+	return strings.Join(descriptions, "\n")
+}
+
+func (a *MultiArtifact) State(name string) interface{} {
+	states := make([]interface{}, len(a.Artifacts))
+	for i, artifact := range a.Artifacts {
+		states[i] = artifact.State(name)
+	}
+	return states
+}
+
+// Destroy destroys every artifact in the matrix, returning the first error
+// encountered, if any.
+func (a *MultiArtifact) Destroy() error {
+	for _, artifact := range a.Artifacts {
+		if err := artifact.Destroy(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package classic
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepExportImage tars, compresses, chunks and uploads the master volume
+// attached to the builder instance. When Config.BuilderUploadImageCommand
+// is set it is run verbatim, for backward compatibility with templates
+// written against the single-command pipeline. Otherwise the pipeline is
+// built from Config.ExportFormat via an ImageExporter.
+type stepExportImage struct {
+	UploadImageCommand string
+	ExportFormat       string
+	ExportChunkSize    int64
+}
+
+const exportTarPath = "/tmp/image.tar"
+
+func (s *stepExportImage) Run(state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+	comm := state.Get("communicator").(packer.Communicator)
+
+	commands, err := s.commands()
+	if err != nil {
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	for _, command := range commands {
+		ui.Say(fmt.Sprintf("Exporting image: %s", command))
+		cmd := &packer.RemoteCmd{Command: command}
+		if err := cmd.StartWithUi(comm, ui); err != nil {
+			state.Put("error", fmt.Errorf("error exporting image: %s", err))
+			return multistep.ActionHalt
+		}
+		if cmd.ExitStatus != 0 {
+			state.Put("error", fmt.Errorf("export command %q exited with status %d", command, cmd.ExitStatus))
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepExportImage) Cleanup(multistep.StateBag) {}
+
+// commands returns the ordered shell commands that make up the export
+// pipeline for this step's configuration.
+func (s *stepExportImage) commands() ([]string, error) {
+	if s.UploadImageCommand != "" {
+		return []string{s.UploadImageCommand}, nil
+	}
+
+	exporter, err := NewImageExporter(s.ExportFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkSize := s.ExportChunkSize
+	if chunkSize == 0 {
+		chunkSize = exportDefaultChunkSize
+	}
+
+	tarCmd := exporter.Tar("/dev/xvdc", exportTarPath)
+	compressCmd, compressedPath := exporter.Compress(exportTarPath)
+	splitCmd, chunkGlob := exporter.Split(compressedPath, chunkSize)
+
+	return []string{
+		tarCmd,
+		compressCmd,
+		splitCmd,
+		exporter.Upload(chunkGlob),
+	}, nil
+}
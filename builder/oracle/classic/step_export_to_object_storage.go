@@ -0,0 +1,111 @@
+package classic
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepExportToObjectStorage uploads the machine image tarball produced by
+// stepCreateImage into an OCI Object Storage bucket with a single HTTP PUT
+// to ObjectStorageAccessURI, which is either a pre-authenticated request
+// URL or a plain Object Storage endpoint (see objectStorageUploadURL). It
+// runs only when Config.ObjectStorageBucket is set.
+type stepExportToObjectStorage struct{}
+
+func (s *stepExportToObjectStorage) Run(state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if config.ObjectStorageBucket == "" {
+		return multistep.ActionContinue
+	}
+
+	imageFile, ok := state.GetOk("machine_image_file")
+	if !ok {
+		state.Put("error", fmt.Errorf("cannot export to object storage: no machine image file was produced"))
+		return multistep.ActionHalt
+	}
+	localPath := imageFile.(string)
+
+	objectName := filepath.Base(localPath)
+	if config.ObjectStorageObjectPrefix != "" {
+		objectName = config.ObjectStorageObjectPrefix + objectName
+	}
+
+	ui.Say(fmt.Sprintf("Exporting machine image to Object Storage bucket %q as %q...", config.ObjectStorageBucket, objectName))
+
+	url, err := uploadToObjectStorage(config, localPath, objectName)
+	if err != nil {
+		state.Put("error", fmt.Errorf("error exporting image to Object Storage: %s", err))
+		return multistep.ActionHalt
+	}
+
+	state.Put("object_storage_urls", []string{url})
+	ui.Message(fmt.Sprintf("Uploaded image to: %s", url))
+
+	return multistep.ActionContinue
+}
+
+func (s *stepExportToObjectStorage) Cleanup(multistep.StateBag) {}
+
+// objectStorageUploadURL builds the URL to PUT objectName to. When
+// ObjectStorageAccessURI is a pre-authenticated request (PAR) URL
+// (identifiable by its "/p/" token segment) it already encodes the
+// namespace and bucket path up to the token, so objectName is appended to
+// it directly. Otherwise ObjectStorageAccessURI is treated as a plain
+// Object Storage service endpoint, and the namespace/bucket are appended to
+// build the native "/n/{namespace}/b/{bucket}/o/{object}" path.
+func objectStorageUploadURL(config *Config, objectName string) (uploadURL string, isPAR bool) {
+	base := strings.TrimRight(config.ObjectStorageAccessURI, "/")
+	if strings.Contains(base, "/p/") {
+		return base + "/" + objectName, true
+	}
+	return fmt.Sprintf("%s/n/%s/b/%s/o/%s", base, config.ObjectStorageNamespace, config.ObjectStorageBucket, objectName), false
+}
+
+// uploadToObjectStorage PUTs the file at localPath to the URL built by
+// objectStorageUploadURL, authenticating with the account's standard
+// Swift-compatible username and password unless the URL is already a PAR
+// (which needs no further credentials).
+func uploadToObjectStorage(config *Config, localPath, objectName string) (string, error) {
+	uploadURL, isPAR := objectStorageUploadURL(config, objectName)
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", fmt.Errorf("could not open %q for upload: %s", localPath, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("could not stat %q: %s", localPath, err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, uploadURL, f)
+	if err != nil {
+		return "", fmt.Errorf("could not build upload request for %q: %s", localPath, err)
+	}
+	req.ContentLength = info.Size()
+	if !isPAR {
+		req.SetBasicAuth(config.Username, config.Password)
+	}
+
+	resp, err := cleanhttp.DefaultClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error uploading %q to Object Storage: %s", localPath, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("Object Storage rejected upload of %q: %s", localPath, resp.Status)
+	}
+
+	return uploadURL, nil
+}
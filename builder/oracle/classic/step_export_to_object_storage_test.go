@@ -0,0 +1,37 @@
+package classic
+
+import "testing"
+
+func TestObjectStorageUploadURL_plainAccessURI(t *testing.T) {
+	config := &Config{
+		ObjectStorageNamespace: "mynamespace",
+		ObjectStorageBucket:    "mybucket",
+		ObjectStorageAccessURI: "https://objectstorage.us-ashburn-1.oraclecloud.com",
+	}
+
+	url, isPAR := objectStorageUploadURL(config, "image.tar.gz")
+	if isPAR {
+		t.Fatal("expected a plain access URI to not be detected as a PAR")
+	}
+	want := "https://objectstorage.us-ashburn-1.oraclecloud.com/n/mynamespace/b/mybucket/o/image.tar.gz"
+	if url != want {
+		t.Fatalf("got URL %q, want %q", url, want)
+	}
+}
+
+func TestObjectStorageUploadURL_preAuthenticatedRequest(t *testing.T) {
+	config := &Config{
+		ObjectStorageNamespace: "mynamespace",
+		ObjectStorageBucket:    "mybucket",
+		ObjectStorageAccessURI: "https://objectstorage.us-ashburn-1.oraclecloud.com/p/sometoken/n/mynamespace/b/mybucket/o/",
+	}
+
+	url, isPAR := objectStorageUploadURL(config, "image.tar.gz")
+	if !isPAR {
+		t.Fatal("expected a /p/ access URI to be detected as a PAR")
+	}
+	want := "https://objectstorage.us-ashburn-1.oraclecloud.com/p/sometoken/n/mynamespace/b/mybucket/o/image.tar.gz"
+	if url != want {
+		t.Fatalf("got URL %q, want %q; a PAR's path already encodes the namespace and bucket, so it must not be appended again", url, want)
+	}
+}
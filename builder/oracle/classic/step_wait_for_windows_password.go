@@ -0,0 +1,77 @@
+package classic
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-oracle-terraform/compute"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepWaitForWindowsPassword polls the instance attributes OPC populates
+// once cloud-init has generated the local Administrator password, analogous
+// to how the Amazon builder waits for EC2's password-data blob on Windows
+// AMIs. It is a no-op for non-WinRM communicators, or once a password has
+// already been supplied in the template.
+type stepWaitForWindowsPassword struct {
+	// Comm is the communicator config that will be used to connect to the
+	// instance; its WinRMPassword is populated once retrieved.
+	Comm *communicator.Config
+	// InstanceInfoKey is the state bag key under which the step can find
+	// the *compute.InstanceInfo whose attributes should be polled.
+	InstanceInfoKey string
+}
+
+const windowsPasswordAttribute = "windows_password"
+
+func (s *stepWaitForWindowsPassword) Run(state multistep.StateBag) multistep.StepAction {
+	if s.Comm.Type != "winrm" || s.Comm.WinRMPassword != "" {
+		return multistep.ActionContinue
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	client := state.Get("client").(*compute.ComputeClient)
+
+	rawInstanceInfo, ok := state.GetOk(s.InstanceInfoKey)
+	if !ok {
+		state.Put("error", fmt.Errorf("cannot wait for Windows Administrator password: no instance info at state key %q", s.InstanceInfoKey))
+		return multistep.ActionHalt
+	}
+	instanceInfo, ok := rawInstanceInfo.(*compute.InstanceInfo)
+	if !ok {
+		state.Put("error", fmt.Errorf("cannot wait for Windows Administrator password: state key %q did not hold a *compute.InstanceInfo", s.InstanceInfoKey))
+		return multistep.ActionHalt
+	}
+
+	ui.Say("Waiting for the Windows Administrator password to become available...")
+
+	password, err := waitForWindowsPassword(client, instanceInfo.Name, 10*time.Minute)
+	if err != nil {
+		state.Put("error", fmt.Errorf("error retrieving Windows Administrator password: %s", err))
+		return multistep.ActionHalt
+	}
+
+	s.Comm.WinRMPassword = password
+	return multistep.ActionContinue
+}
+
+func (s *stepWaitForWindowsPassword) Cleanup(multistep.StateBag) {}
+
+// waitForWindowsPassword polls the instance's attributes for
+// windowsPasswordAttribute until it is populated or timeout elapses.
+func waitForWindowsPassword(client *compute.ComputeClient, instanceName string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		instance, err := client.Instances().GetInstance(&compute.GetInstanceInput{Name: instanceName})
+		if err != nil {
+			return "", err
+		}
+		if password, ok := instance.Attributes[windowsPasswordAttribute].(string); ok && password != "" {
+			return password, nil
+		}
+		time.Sleep(5 * time.Second)
+	}
+	return "", fmt.Errorf("timed out waiting for %q attribute on instance %q", windowsPasswordAttribute, instanceName)
+}